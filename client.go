@@ -1,7 +1,6 @@
 package hub
 
 import (
-	"encoding/json"
 	"time"
 
 	"github.com/gorilla/websocket"
@@ -22,31 +21,95 @@ var (
 )
 
 // Subscription represents a 1:1 relationship between topic and client.
+// LastSeq is the sequence number the client already has for Topic, if
+// any; the hub replays anything published after it before live delivery
+// begins.
 type Subscription struct {
-	Topic  string
-	Client *Client
+	Topic   string
+	Client  *Client
+	LastSeq uint64
 }
 
 // Client represents a single connection from a user.
 type Client struct {
-	ID     string
-	ws     *websocket.Conn
-	hub    *Hub
-	closed bool
-	send   chan []byte
-	Topics []string
+	ID       string
+	ws       *websocket.Conn
+	hub      *Hub
+	closed   bool
+	send     chan []byte
+	Topics   []string
+	batch    bool
+	codec    Codec
+	policy   SlowConsumerPolicy
+	compress bool
 }
 
-// NewClient creates a new client.
+// SlowConsumerPolicy controls what the hub does when a Client's send
+// buffer is full and another message needs to be queued for it.
+type SlowConsumerPolicy int
+
+const (
+	// PolicyBlock blocks the hub's fan-out until the client drains its
+	// queue. This is the default, matching the hub's original behavior.
+	PolicyBlock SlowConsumerPolicy = iota
+	// PolicyDropOldest discards the oldest queued message to make room
+	// for the new one.
+	PolicyDropOldest
+	// PolicyDropNewest discards the incoming message, leaving the queue
+	// as it was.
+	PolicyDropNewest
+	// PolicyDisconnect disconnects the client.
+	PolicyDisconnect
+)
+
+// NewClient creates a new client. Batching of queued messages into a
+// single frame is enabled by default; call SetBatching(false) for
+// clients that opt out (e.g. via a `?batch=0` query flag at upgrade
+// time). The client defaults to JSONCodec; call SetCodec to negotiate a
+// different wire format (e.g. via CodecForSubprotocol at upgrade time).
+// The send buffer size defaults to the hub's configuration and the
+// slow-consumer policy to PolicyBlock; call SetSlowConsumerPolicy to
+// override the policy.
+// Compression is enabled by default; call SetCompression(false) for
+// clients that can't transparently gunzip a payload.
 func NewClient(ws *websocket.Conn, h *Hub, ID string) *Client {
 	return &Client{
-		ID:   ID,
-		send: make(chan []byte, 256),
-		ws:   ws,
-		hub:  h,
+		ID:       ID,
+		send:     make(chan []byte, h.clientSendBuffer()),
+		ws:       ws,
+		hub:      h,
+		batch:    true,
+		codec:    JSONCodec{},
+		policy:   PolicyBlock,
+		compress: true,
 	}
 }
 
+// SetBatching enables or disables coalescing of queued messages into a
+// single WebSocket frame for this client.
+func (c *Client) SetBatching(enabled bool) {
+	c.batch = enabled
+}
+
+// SetSlowConsumerPolicy selects what happens when this client's send
+// buffer fills up.
+func (c *Client) SetSlowConsumerPolicy(policy SlowConsumerPolicy) {
+	c.policy = policy
+}
+
+// SetCodec selects the wire format this client's messages are encoded
+// and decoded with.
+func (c *Client) SetCodec(codec Codec) {
+	c.codec = codec
+}
+
+// SetCompression enables or disables gzip compression of payloads
+// delivered to this client, letting heterogeneous clients negotiate the
+// capability independently of one another.
+func (c *Client) SetCompression(enabled bool) {
+	c.compress = enabled
+}
+
 // AddTopic adds a topic to a client.
 func (c *Client) AddTopic(topic string) {
 	c.Topics = append(c.Topics, topic)
@@ -72,9 +135,17 @@ func (c *Client) RemoveTopic(topic string) {
 
 // Subscribe subscribes a client to a topic.
 func (c *Client) Subscribe(topic string) {
+	c.SubscribeFrom(topic, 0)
+}
+
+// SubscribeFrom subscribes a client to a topic, replaying any persisted
+// messages with a sequence number greater than lastSeq before live
+// delivery begins.
+func (c *Client) SubscribeFrom(topic string, lastSeq uint64) {
 	s := &Subscription{
-		Topic:  topic,
-		Client: c,
+		Topic:   topic,
+		Client:  c,
+		LastSeq: lastSeq,
 	}
 	c.hub.subscribe <- s
 }
@@ -86,6 +157,14 @@ func (c *Client) SubscribeMultiple(topics []string) {
 	}
 }
 
+// SubscribeMultipleFrom subscribes the client to multiple topics,
+// replaying each from the sequence number given in lastSeq, if any.
+func (c *Client) SubscribeMultipleFrom(topics []string, lastSeq map[string]uint64) {
+	for _, topic := range topics {
+		c.SubscribeFrom(topic, lastSeq[topic])
+	}
+}
+
 func (c *Client) Unsubscribe(topic string) {
 	s := &Subscription{
 		Topic:  topic,
@@ -100,10 +179,14 @@ func (c *Client) UnsubscribeAll() {
 	}
 }
 
-// close closes the websocket and the send channel.
+// close closes the websocket, if any, and the send channel. Virtual
+// clients synthesized for non-WebSocket transports have no ws and skip
+// straight to closing send.
 func (c *Client) close() {
 	if !c.closed {
-		if err := c.ws.Close(); err != nil {
+		if c.ws == nil {
+			close(c.send)
+		} else if err := c.ws.Close(); err != nil {
 			c.hub.log.Println("[DEBUG] websocket was already closed:", err)
 		} else {
 			c.hub.log.Println("[DEBUG] websocket closed.")
@@ -143,7 +226,7 @@ func (c *Client) listenRead() {
 
 		actionMessage := &ActionMessage{}
 		// message contains the topic to which user is subscribing to
-		if err := json.Unmarshal(payload, actionMessage); err != nil {
+		if err := c.codec.Decode(payload, actionMessage); err != nil {
 			c.hub.log.Printf(
 				"[ERROR] invalid data sent for subscription:%v\n",
 				actionMessage,
@@ -156,17 +239,17 @@ func (c *Client) listenRead() {
 			c.hub.log.Printf("[DEBUG] Client %s is subscribing. Removing all old subscriptions.", c.ID)
 			c.UnsubscribeAll()
 			subMsg := &SubscriptionsMessage{}
-			if err := json.Unmarshal(payload, subMsg); err != nil {
+			if err := c.codec.Decode(payload, subMsg); err != nil {
 				c.hub.log.Printf(
 					"[ERROR] invalid data sent for subscription:%v\n",
 					actionMessage,
 				)
 				continue
 			}
-			c.SubscribeMultiple(subMsg.Topics)
+			c.SubscribeMultipleFrom(subMsg.Topics, subMsg.LastSeq)
 		default:
 			pubMsg := &PublishMessage{}
-			if err := json.Unmarshal(payload, pubMsg); err != nil {
+			if err := c.codec.Decode(payload, pubMsg); err != nil {
 				c.hub.log.Printf(
 					"[ERROR] invalid data sent for subscription:%v\n",
 					actionMessage,
@@ -195,6 +278,44 @@ func (c *Client) listenWrite() {
 		c.ws.Close()
 	}()
 
+	writeBatch := func(batch [][]byte) error {
+		// Bracket-concatenation into a single JSON array only holds up
+		// for the JSON codec; other wire formats fall back to one frame
+		// per message.
+		if len(batch) == 1 || c.codec.ContentType() != (JSONCodec{}).ContentType() {
+			for _, m := range batch {
+				if err := write(websocket.TextMessage, m); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+		if err := c.ws.SetWriteDeadline(time.Now().Add(writeWait)); err != nil {
+			return err
+		}
+		w, err := c.ws.NextWriter(websocket.TextMessage)
+		if err != nil {
+			return err
+		}
+		if _, err := w.Write([]byte{'['}); err != nil {
+			return err
+		}
+		for i, m := range batch {
+			if i > 0 {
+				if _, err := w.Write([]byte{','}); err != nil {
+					return err
+				}
+			}
+			if _, err := w.Write(m); err != nil {
+				return err
+			}
+		}
+		if _, err := w.Write([]byte{']'}); err != nil {
+			return err
+		}
+		return w.Close()
+	}
+
 	for {
 		select {
 		// listen for messages
@@ -207,8 +328,38 @@ func (c *Client) listenWrite() {
 				}
 				return
 			}
-			// write to ws
-			if err := write(websocket.TextMessage, message); err != nil {
+
+			if !c.batch {
+				if err := write(websocket.TextMessage, message); err != nil {
+					c.hub.log.Println("[ERROR] failed to write socket message:", err)
+					return
+				}
+				continue
+			}
+
+			// drain whatever else is already queued so it can be sent
+			// as a single frame instead of one WriteMessage per payload.
+			batch := [][]byte{message}
+		drain:
+			for len(batch) < c.hub.maxBatchSize() {
+				select {
+				case m, ok := <-c.send:
+					if !ok {
+						if err := writeBatch(batch); err != nil {
+							c.hub.log.Println("[ERROR] failed to write socket message:", err)
+							return
+						}
+						if err := write(websocket.CloseMessage, []byte{}); err != nil {
+							c.hub.log.Println("[ERROR] socket already closed:", err)
+						}
+						return
+					}
+					batch = append(batch, m)
+				default:
+					break drain
+				}
+			}
+			if err := writeBatch(batch); err != nil {
 				c.hub.log.Println("[ERROR] failed to write socket message:", err)
 				return
 			}
@@ -0,0 +1,112 @@
+package hub
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// wsPair dials a server Client through a real loopback WebSocket
+// connection so listenWrite's framing can be exercised end to end, and
+// returns the server-side Client (not yet draining) plus a dialer conn to
+// read frames from.
+func wsPair(t *testing.T, configure func(c *Client)) (*Client, *websocket.Conn) {
+	t.Helper()
+	h := NewHub(log.New(io.Discard, "", 0), Config{})
+
+	var serverClient *Client
+	ready := make(chan struct{})
+	upgrader := websocket.Upgrader{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			t.Errorf("upgrade: %v", err)
+			return
+		}
+		serverClient = NewClient(conn, h, "srv")
+		if configure != nil {
+			configure(serverClient)
+		}
+		close(ready)
+	}))
+	t.Cleanup(srv.Close)
+
+	wsURL := "ws" + strings.TrimPrefix(srv.URL, "http")
+	dialConn, _, err := websocket.DefaultDialer.Dial(wsURL, nil)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	t.Cleanup(func() { dialConn.Close() })
+
+	<-ready
+	return serverClient, dialConn
+}
+
+func TestListenWriteBatchesQueuedMessagesIntoOneFrame(t *testing.T) {
+	c, dialConn := wsPair(t, nil)
+
+	c.send <- []byte(`{"n":1}`)
+	c.send <- []byte(`{"n":2}`)
+	c.send <- []byte(`{"n":3}`)
+	go c.listenWrite()
+
+	dialConn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	_, frame, err := dialConn.ReadMessage()
+	if err != nil {
+		t.Fatalf("ReadMessage: %v", err)
+	}
+
+	var batch []json.RawMessage
+	if err := json.Unmarshal(frame, &batch); err != nil {
+		t.Fatalf("frame %q is not a JSON array: %v", frame, err)
+	}
+	if len(batch) != 3 {
+		t.Fatalf("batch has %d elements, want 3", len(batch))
+	}
+}
+
+func TestListenWriteSendsOneFramePerMessageWhenBatchingDisabled(t *testing.T) {
+	c, dialConn := wsPair(t, func(c *Client) { c.SetBatching(false) })
+
+	c.send <- []byte(`{"n":1}`)
+	c.send <- []byte(`{"n":2}`)
+	go c.listenWrite()
+
+	for i := 0; i < 2; i++ {
+		dialConn.SetReadDeadline(time.Now().Add(2 * time.Second))
+		_, frame, err := dialConn.ReadMessage()
+		if err != nil {
+			t.Fatalf("ReadMessage %d: %v", i, err)
+		}
+		var batch []json.RawMessage
+		if err := json.Unmarshal(frame, &batch); err == nil {
+			t.Fatalf("frame %q should not be a JSON array when batching is disabled", frame)
+		}
+	}
+}
+
+func TestListenWriteFallsBackToOneFramePerMessageForNonJSONCodec(t *testing.T) {
+	c, dialConn := wsPair(t, func(c *Client) { c.SetCodec(MsgpackCodec{}) })
+
+	c.send <- []byte("one")
+	c.send <- []byte("two")
+	go c.listenWrite()
+
+	for i, want := range []string{"one", "two"} {
+		dialConn.SetReadDeadline(time.Now().Add(2 * time.Second))
+		_, frame, err := dialConn.ReadMessage()
+		if err != nil {
+			t.Fatalf("ReadMessage %d: %v", i, err)
+		}
+		if string(frame) != want {
+			t.Fatalf("frame %d = %q, want %q (no bracket-wrapping for a non-JSON codec)", i, frame, want)
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package hub
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// Subprotocol names a client can request at upgrade time to select a
+// Codec, e.g. via the Sec-WebSocket-Protocol header.
+//
+// A protobuf subprotocol isn't offered yet: ActionMessage,
+// SubscriptionsMessage and PublishMessage have no protobuf-generated
+// equivalents, so there's nothing for a ProtobufCodec to encode. Add
+// SubprotocolProtobuf once those types (or wrappers around them) exist.
+const (
+	SubprotocolJSON    = "pubsub.json.v1"
+	SubprotocolMsgpack = "pubsub.msgpack.v1"
+)
+
+// Codec encodes and decodes the messages a Client exchanges with the
+// hub, letting heterogeneous clients negotiate their own wire format.
+type Codec interface {
+	Decode(data []byte, v interface{}) error
+	Encode(v interface{}) ([]byte, error)
+	ContentType() string
+}
+
+// CodecForSubprotocol returns the Codec a client negotiated via name,
+// falling back to JSONCodec for an empty or unrecognized subprotocol.
+func CodecForSubprotocol(name string) Codec {
+	switch name {
+	case SubprotocolMsgpack:
+		return MsgpackCodec{}
+	default:
+		return JSONCodec{}
+	}
+}
+
+// JSONCodec is the hub's original, default wire format.
+type JSONCodec struct{}
+
+func (JSONCodec) ContentType() string { return "application/json" }
+
+func (JSONCodec) Decode(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
+
+func (JSONCodec) Encode(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+// MsgpackCodec encodes messages as MessagePack, for clients that want a
+// more compact binary wire format without changing the Go message
+// types.
+type MsgpackCodec struct{}
+
+func (MsgpackCodec) ContentType() string { return "application/msgpack" }
+
+func (MsgpackCodec) Decode(data []byte, v interface{}) error {
+	return msgpack.Unmarshal(data, v)
+}
+
+func (MsgpackCodec) Encode(v interface{}) ([]byte, error) {
+	return msgpack.Marshal(v)
+}
+
+// EncodingGzip marks a PublishMessage payload as gzip-compressed in
+// PublishMessage.Encoding.
+const EncodingGzip = "gzip"
+
+// compressPayload gzips payload when it exceeds threshold, returning the
+// encoding name to stamp on the message, or "" if left uncompressed.
+// threshold <= 0 disables compression.
+func compressPayload(payload []byte, threshold int) ([]byte, string, error) {
+	if threshold <= 0 || len(payload) <= threshold {
+		return payload, "", nil
+	}
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	if _, err := gz.Write(payload); err != nil {
+		return nil, "", err
+	}
+	if err := gz.Close(); err != nil {
+		return nil, "", err
+	}
+	return buf.Bytes(), EncodingGzip, nil
+}
+
+// DecompressPayload reverses compressPayload for a received
+// PublishMessage, returning the payload unchanged if it isn't
+// compressed.
+func DecompressPayload(msg PublishMessage) ([]byte, error) {
+	if msg.Encoding != EncodingGzip {
+		return msg.Payload, nil
+	}
+	gz, err := gzip.NewReader(bytes.NewReader(msg.Payload))
+	if err != nil {
+		return nil, err
+	}
+	defer gz.Close()
+	return io.ReadAll(gz)
+}
@@ -0,0 +1,159 @@
+package hub
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultLongPollTimeout bounds how long a GET /topics/{topic} request
+// waits for the next message before returning 204 No Content.
+const DefaultLongPollTimeout = 30 * time.Second
+
+// Handler serves an HTTP gateway onto a Hub so non-WebSocket clients can
+// publish and subscribe.
+type Handler struct {
+	hub             *Hub
+	longPollTimeout time.Duration
+}
+
+// NewHandler creates an HTTP gateway backed by hub.
+func NewHandler(h *Hub) *Handler {
+	return &Handler{hub: h, longPollTimeout: DefaultLongPollTimeout}
+}
+
+var httpClientSeq uint64
+
+func nextHTTPClientID() string {
+	return "http-" + strconv.FormatUint(atomic.AddUint64(&httpClientSeq, 1), 10)
+}
+
+// ServeHTTP routes:
+//
+//	POST /topics/{topic}          publish payload, returns the assigned seq
+//	GET  /topics/{topic}?since=N  long-poll for the next message after seq N
+//	GET  /topics                  list active topics with subscriber counts
+//	GET  /sources                 list registered EventSources and their status
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == "/topics" {
+		if r.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		h.listTopics(w, r)
+		return
+	}
+
+	if r.URL.Path == "/sources" {
+		if r.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		writeJSON(w, http.StatusOK, h.hub.SourceStatuses())
+		return
+	}
+
+	topic := strings.TrimPrefix(r.URL.Path, "/topics/")
+	if topic == "" || topic == r.URL.Path {
+		http.NotFound(w, r)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPost:
+		h.publish(w, r, topic)
+	case http.MethodGet:
+		h.poll(w, r, topic)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (h *Handler) publish(w http.ResponseWriter, r *http.Request, topic string) {
+	payload, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, "failed to read request body", http.StatusBadRequest)
+		return
+	}
+
+	seq, err := h.hub.PublishSync(PublishMessage{Topic: topic, Payload: payload})
+	if err != nil {
+		http.Error(w, "failed to publish message", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, struct {
+		Seq uint64 `json:"seq"`
+	}{Seq: seq})
+}
+
+// poll long-polls for the next message published to topic after since,
+// by subscribing a virtual Client and waiting for the hub to either
+// replay a persisted message or deliver the next live one.
+func (h *Handler) poll(w http.ResponseWriter, r *http.Request, topic string) {
+	var since uint64
+	if s := r.URL.Query().Get("since"); s != "" {
+		v, err := strconv.ParseUint(s, 10, 64)
+		if err != nil {
+			http.Error(w, "invalid since", http.StatusBadRequest)
+			return
+		}
+		since = v
+	}
+
+	vc := NewClient(nil, h.hub, nextHTTPClientID())
+	// Plain HTTP callers have no subprotocol negotiation and so no way to
+	// opt out of compression the way a WebSocket client can via
+	// SetCompression(false); default them to uncompressed instead of
+	// silently handing back gzipped, undecodable bodies.
+	vc.SetCompression(false)
+	h.hub.register <- vc
+	vc.SubscribeFrom(topic, since)
+	defer func() {
+		vc.UnsubscribeAll()
+		h.hub.unregister <- vc
+	}()
+
+	ctx, cancel := context.WithTimeout(r.Context(), h.longPollTimeout)
+	defer cancel()
+
+	select {
+	case message, ok := <-vc.send:
+		if !ok {
+			http.Error(w, "hub closed", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(message)
+	case <-ctx.Done():
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func (h *Handler) listTopics(w http.ResponseWriter, r *http.Request) {
+	type topicInfo struct {
+		Topic       string `json:"topic"`
+		Subscribers int    `json:"subscribers"`
+	}
+
+	counts := h.hub.Topics()
+	topics := make([]topicInfo, 0, len(counts))
+	for topic, n := range counts {
+		topics = append(topics, topicInfo{Topic: topic, Subscribers: n})
+	}
+	sort.Slice(topics, func(i, j int) bool { return topics[i].Topic < topics[j].Topic })
+
+	writeJSON(w, http.StatusOK, topics)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,99 @@
+package hub
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestHandler(t *testing.T, longPollTimeout time.Duration) *Handler {
+	t.Helper()
+	h := NewHub(log.New(io.Discard, "", 0), Config{})
+	go h.Run()
+	return &Handler{hub: h, longPollTimeout: longPollTimeout}
+}
+
+func TestHandlerPublish(t *testing.T) {
+	handler := newTestHandler(t, time.Second)
+
+	req := httptest.NewRequest(http.MethodPost, "/topics/orders", strings.NewReader("hello"))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	var body struct {
+		Seq uint64 `json:"seq"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if body.Seq != 1 {
+		t.Fatalf("seq = %d, want 1", body.Seq)
+	}
+}
+
+func TestHandlerListTopics(t *testing.T) {
+	handler := newTestHandler(t, time.Second)
+
+	publishReq := httptest.NewRequest(http.MethodPost, "/topics/orders", strings.NewReader("hello"))
+	handler.ServeHTTP(httptest.NewRecorder(), publishReq)
+
+	req := httptest.NewRequest(http.MethodGet, "/topics", nil)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	var topics []struct {
+		Topic       string `json:"topic"`
+		Subscribers int    `json:"subscribers"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&topics); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if len(topics) != 1 || topics[0].Topic != "orders" {
+		t.Fatalf("topics = %+v, want one entry for orders", topics)
+	}
+}
+
+func TestHandlerPollReplaysPersistedMessage(t *testing.T) {
+	handler := newTestHandler(t, time.Second)
+
+	publishReq := httptest.NewRequest(http.MethodPost, "/topics/orders", strings.NewReader("hello"))
+	handler.ServeHTTP(httptest.NewRecorder(), publishReq)
+
+	pollReq := httptest.NewRequest(http.MethodGet, "/topics/orders?since=0", nil)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, pollReq)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	var msg PublishMessage
+	if err := json.NewDecoder(rec.Body).Decode(&msg); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if string(msg.Payload) != "hello" {
+		t.Fatalf("payload = %q, want %q", msg.Payload, "hello")
+	}
+}
+
+func TestHandlerPollTimesOutWithNoContent(t *testing.T) {
+	handler := newTestHandler(t, 50*time.Millisecond)
+
+	req := httptest.NewRequest(http.MethodGet, "/topics/empty", nil)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusNoContent)
+	}
+}
@@ -0,0 +1,407 @@
+package hub
+
+import (
+	"log"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Hub maintains the set of active clients, their topic subscriptions,
+// and fans published messages out to the appropriate subscribers.
+type Hub struct {
+	log *log.Logger
+
+	store                Store
+	retentionMaxMessages int
+	retentionMaxAge      time.Duration
+	batchSize            int
+	compressionThreshold int
+	sendBuffer           int
+	metrics              *Metrics
+
+	mu              sync.RWMutex
+	clients         map[*Client]bool
+	topics          *topicTrie
+	publishedTopics map[string]bool
+	sources         map[string]*sourceSupervisor
+
+	register    chan *Client
+	unregister  chan *Client
+	subscribe   chan *Subscription
+	unsubscribe chan *Subscription
+	publish     chan *publishRequest
+}
+
+// publishRequest carries a message to be dispatched by the hub's Run
+// loop, optionally reporting the assigned sequence number back to the
+// caller (used by synchronous publishers such as the HTTP gateway).
+type publishRequest struct {
+	msg    PublishMessage
+	result chan<- publishResult
+}
+
+// publishResult reports the outcome of a publishRequest.
+type publishResult struct {
+	seq uint64
+	err error
+}
+
+// Config configures persistence and retention for a Hub.
+type Config struct {
+	// Store persists published messages so new subscribers can replay
+	// history. Defaults to a MemoryStore if nil.
+	Store Store
+	// RetentionMaxMessages caps the number of retained messages per
+	// topic. Zero means unlimited.
+	RetentionMaxMessages int
+	// RetentionMaxAge caps how long a message is retained. Zero means
+	// unlimited.
+	RetentionMaxAge time.Duration
+	// CompactionInterval controls how often retention limits are
+	// enforced in the background. Zero disables background compaction.
+	CompactionInterval time.Duration
+	// MaxBatchSize caps how many queued messages a client's writer will
+	// coalesce into a single frame. Zero uses defaultMaxBatchSize.
+	MaxBatchSize int
+	// CompressionThreshold gzips a publish payload larger than this many
+	// bytes before delivering it to clients that haven't opted out via
+	// Client.SetCompression(false). Zero (or negative) disables
+	// compression. Only gzip is implemented; brotli is not offered.
+	CompressionThreshold int
+	// ClientSendBuffer sets the default size of a client's outbound
+	// message buffer. Zero uses defaultSendBuffer.
+	ClientSendBuffer int
+}
+
+// defaultMaxBatchSize is used when Config.MaxBatchSize is unset.
+const defaultMaxBatchSize = 32
+
+// defaultSendBuffer is used when Config.ClientSendBuffer is unset.
+const defaultSendBuffer = 256
+
+// NewHub creates a Hub ready to Run.
+func NewHub(logger *log.Logger, cfg Config) *Hub {
+	if cfg.Store == nil {
+		cfg.Store = NewMemoryStore()
+	}
+	if cfg.MaxBatchSize <= 0 {
+		cfg.MaxBatchSize = defaultMaxBatchSize
+	}
+	if cfg.ClientSendBuffer <= 0 {
+		cfg.ClientSendBuffer = defaultSendBuffer
+	}
+	h := &Hub{
+		log:                  logger,
+		store:                cfg.Store,
+		retentionMaxMessages: cfg.RetentionMaxMessages,
+		retentionMaxAge:      cfg.RetentionMaxAge,
+		batchSize:            cfg.MaxBatchSize,
+		compressionThreshold: cfg.CompressionThreshold,
+		sendBuffer:           cfg.ClientSendBuffer,
+		metrics:              newMetrics(),
+		clients:              make(map[*Client]bool),
+		topics:               newTopicTrie(),
+		publishedTopics:      make(map[string]bool),
+		sources:              make(map[string]*sourceSupervisor),
+		register:             make(chan *Client),
+		unregister:           make(chan *Client),
+		subscribe:            make(chan *Subscription),
+		unsubscribe:          make(chan *Subscription),
+		publish:              make(chan *publishRequest),
+	}
+	if cfg.CompactionInterval > 0 {
+		go h.compactionLoop(cfg.CompactionInterval)
+	}
+	return h
+}
+
+// Run starts the hub's main loop, dispatching register, subscribe and
+// publish events as they arrive. It blocks, so callers should run it in
+// its own goroutine.
+func (h *Hub) Run() {
+	for {
+		select {
+		case c := <-h.register:
+			h.mu.Lock()
+			h.clients[c] = true
+			h.mu.Unlock()
+		case c := <-h.unregister:
+			h.removeClient(c)
+		case s := <-h.subscribe:
+			h.mu.Lock()
+			h.addToTopic(s.Topic, s.Client)
+			h.mu.Unlock()
+			s.Client.AddTopic(s.Topic)
+			h.replay(s)
+		case s := <-h.unsubscribe:
+			h.mu.Lock()
+			h.removeFromTopic(s.Topic, s.Client)
+			h.mu.Unlock()
+		case req := <-h.publish:
+			h.dispatch(req)
+		}
+	}
+}
+
+// removeClient unregisters c: it leaves every topic it was subscribed
+// to, clears its tracked metrics, and closes its connection.
+func (h *Hub) removeClient(c *Client) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, ok := h.clients[c]; !ok {
+		return
+	}
+	delete(h.clients, c)
+	for _, topic := range append([]string(nil), c.Topics...) {
+		h.removeFromTopic(topic, c)
+	}
+	h.metrics.clearQueueDepth(c.ID)
+	c.close()
+}
+
+func (h *Hub) addToTopic(pattern string, c *Client) {
+	h.topics.insert(pattern, c)
+}
+
+func (h *Hub) removeFromTopic(pattern string, c *Client) {
+	h.topics.remove(pattern, c)
+	c.RemoveTopic(pattern)
+}
+
+// isPattern reports whether topic contains a wildcard segment.
+func isPattern(topic string) bool {
+	for _, seg := range strings.Split(topic, "/") {
+		if seg == wildcardSingle || seg == wildcardMulti {
+			return true
+		}
+	}
+	return false
+}
+
+// replay streams any persisted messages newer than s.LastSeq to the
+// subscribing client before live delivery begins. Wildcard patterns
+// aren't replayed, since persistence is keyed by a single literal topic.
+func (h *Hub) replay(s *Subscription) {
+	if isPattern(s.Topic) {
+		return
+	}
+	records, err := h.store.Read(s.Topic, s.LastSeq)
+	if err != nil {
+		h.log.Println("[ERROR] failed to replay topic", s.Topic, err)
+		return
+	}
+	for _, rec := range records {
+		payload, encoding := rec.Payload, ""
+		if s.Client.compress {
+			compressed, enc, err := compressPayload(rec.Payload, h.compressionThreshold)
+			if err != nil {
+				h.log.Println("[ERROR] failed to compress replayed message:", err)
+			} else {
+				payload, encoding = compressed, enc
+			}
+		}
+		encoded, err := s.Client.codec.Encode(PublishMessage{
+			Topic:    s.Topic,
+			Payload:  payload,
+			Seq:      rec.Seq,
+			Created:  rec.Created,
+			Encoding: encoding,
+		})
+		if err != nil {
+			h.log.Println("[ERROR] failed to encode replayed message:", err)
+			continue
+		}
+		h.deliver(s.Client, encoded)
+	}
+}
+
+// maxBatchSize returns the configured write-batching limit for clients
+// of this hub.
+func (h *Hub) maxBatchSize() int {
+	return h.batchSize
+}
+
+// clientSendBuffer returns the configured default send-buffer size for
+// clients of this hub.
+func (h *Hub) clientSendBuffer() int {
+	return h.sendBuffer
+}
+
+// Metrics returns the hub's backpressure and delivery counters. It
+// implements http.Handler, so operators can mount it wherever they want
+// to expose it, e.g. at /metrics.
+func (h *Hub) Metrics() *Metrics {
+	return h.metrics
+}
+
+// deliver enqueues payload on c.send, applying c's SlowConsumerPolicy if
+// the buffer is already full. Only PolicyBlock waits for room, blocking
+// the caller (normally the Run loop) until the client drains its queue.
+func (h *Hub) deliver(c *Client, payload []byte) {
+	select {
+	case c.send <- payload:
+		h.metrics.setQueueDepth(c.ID, len(c.send))
+		return
+	default:
+	}
+
+	switch c.policy {
+	case PolicyDropNewest:
+		h.metrics.incDropped()
+	case PolicyDropOldest:
+		select {
+		case <-c.send:
+		default:
+		}
+		select {
+		case c.send <- payload:
+		default:
+			h.metrics.incDropped()
+		}
+	case PolicyDisconnect:
+		h.metrics.incDropped()
+		h.removeClient(c)
+		return
+	default: // PolicyBlock
+		c.send <- payload
+	}
+	h.metrics.setQueueDepth(c.ID, len(c.send))
+}
+
+// Publish persists msg and fans it out to every subscriber of its topic.
+func (h *Hub) Publish(msg PublishMessage) {
+	h.publish <- &publishRequest{msg: msg}
+}
+
+// PublishSync persists msg and fans it out like Publish, but blocks until
+// the hub has assigned it a sequence number and returns it. This is used
+// by callers that need to report the sequence back to the publisher,
+// such as the HTTP gateway.
+func (h *Hub) PublishSync(msg PublishMessage) (uint64, error) {
+	result := make(chan publishResult, 1)
+	h.publish <- &publishRequest{msg: msg, result: result}
+	r := <-result
+	return r.seq, r.err
+}
+
+// Topics returns every topic a message has been published to, mapped to
+// the number of clients currently subscribed to a pattern matching it.
+func (h *Hub) Topics() map[string]int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	counts := make(map[string]int, len(h.publishedTopics))
+	for t := range h.publishedTopics {
+		counts[t] = len(h.topics.match(t))
+	}
+	return counts
+}
+
+func (h *Hub) dispatch(req *publishRequest) {
+	start := time.Now()
+	defer func() { h.metrics.observeLatency(time.Since(start)) }()
+
+	msg := req.msg
+	seq, err := h.store.Append(msg.Topic, msg.Payload)
+	if err != nil {
+		h.log.Println("[ERROR] failed to persist message for topic", msg.Topic, err)
+		if req.result != nil {
+			req.result <- publishResult{err: err}
+		}
+		return
+	}
+	msg.Seq = seq
+	msg.Created = time.Now()
+
+	rawPayload := msg.Payload
+	compressedPayload, compressedEncoding, err := compressPayload(msg.Payload, h.compressionThreshold)
+	if err != nil {
+		h.log.Println("[ERROR] failed to compress payload for topic", msg.Topic, err)
+		compressedPayload, compressedEncoding = rawPayload, ""
+	}
+
+	h.mu.Lock()
+	h.publishedTopics[msg.Topic] = true
+	h.mu.Unlock()
+
+	h.mu.RLock()
+	matched := h.topics.match(msg.Topic)
+	recipients := make([]*Client, 0, len(matched))
+	for c := range matched {
+		recipients = append(recipients, c)
+	}
+	h.mu.RUnlock()
+
+	for _, c := range recipients {
+		out := msg
+		if c.compress {
+			out.Payload, out.Encoding = compressedPayload, compressedEncoding
+		} else {
+			out.Payload, out.Encoding = rawPayload, ""
+		}
+		payload, err := c.codec.Encode(out)
+		if err != nil {
+			h.log.Println("[ERROR] failed to encode message for client", c.ID, err)
+			continue
+		}
+		h.deliver(c, payload)
+	}
+
+	if req.result != nil {
+		req.result <- publishResult{seq: seq}
+	}
+}
+
+func (h *Hub) compactionLoop(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		h.compact()
+	}
+}
+
+// compact enforces the configured retention limits against every known
+// topic.
+func (h *Hub) compact() {
+	if h.retentionMaxMessages <= 0 && h.retentionMaxAge <= 0 {
+		return
+	}
+
+	h.mu.RLock()
+	topics := make([]string, 0, len(h.publishedTopics))
+	for t := range h.publishedTopics {
+		topics = append(topics, t)
+	}
+	h.mu.RUnlock()
+
+	for _, topic := range topics {
+		records, err := h.store.Read(topic, 0)
+		if err != nil {
+			h.log.Println("[ERROR] failed to read topic for compaction:", topic, err)
+			continue
+		}
+
+		cutoff := uint64(0)
+		if h.retentionMaxMessages > 0 && len(records) > h.retentionMaxMessages {
+			cutoff = records[len(records)-h.retentionMaxMessages].Seq
+		}
+		if h.retentionMaxAge > 0 {
+			maxAgeCutoff := time.Now().Add(-h.retentionMaxAge)
+			for _, r := range records {
+				if r.Created.Before(maxAgeCutoff) {
+					if r.Seq+1 > cutoff {
+						cutoff = r.Seq + 1
+					}
+					continue
+				}
+				break
+			}
+		}
+		if cutoff > 0 {
+			if err := h.store.Truncate(topic, cutoff); err != nil {
+				h.log.Println("[ERROR] failed to compact topic:", topic, err)
+			}
+		}
+	}
+}
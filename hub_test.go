@@ -0,0 +1,115 @@
+package hub
+
+import (
+	"io"
+	"log"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func newTestHub(t *testing.T, sendBuffer int) *Hub {
+	t.Helper()
+	h := NewHub(log.New(io.Discard, "", 0), Config{ClientSendBuffer: sendBuffer})
+	go h.Run()
+	return h
+}
+
+func registerTestClient(t *testing.T, h *Hub, policy SlowConsumerPolicy) *Client {
+	t.Helper()
+	c := NewClient(nil, h, "c1")
+	c.SetSlowConsumerPolicy(policy)
+	h.register <- c
+	return c
+}
+
+func fillSendBuffer(c *Client, n int) {
+	for i := 0; i < n; i++ {
+		c.send <- []byte{byte(i)}
+	}
+}
+
+func TestDeliverPolicyBlock(t *testing.T) {
+	h := newTestHub(t, 1)
+	c := registerTestClient(t, h, PolicyBlock)
+	fillSendBuffer(c, 1)
+
+	done := make(chan struct{})
+	go func() {
+		h.deliver(c, []byte("second"))
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		t.Fatal("deliver returned before the full buffer was drained")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	<-c.send // drain the first message, making room
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("deliver did not unblock after the buffer drained")
+	}
+	if got := <-c.send; string(got) != "second" {
+		t.Fatalf("send channel = %q, want %q", got, "second")
+	}
+}
+
+func TestDeliverPolicyDropNewest(t *testing.T) {
+	h := newTestHub(t, 2)
+	c := registerTestClient(t, h, PolicyDropNewest)
+	fillSendBuffer(c, 2)
+
+	h.deliver(c, []byte("dropped"))
+
+	if got := atomic.LoadUint64(&h.metrics.droppedTotal); got != 1 {
+		t.Fatalf("droppedTotal = %d, want 1", got)
+	}
+	if len(c.send) != 2 {
+		t.Fatalf("send channel len = %d, want 2 (incoming message discarded)", len(c.send))
+	}
+}
+
+func TestDeliverPolicyDropOldest(t *testing.T) {
+	h := newTestHub(t, 2)
+	c := registerTestClient(t, h, PolicyDropOldest)
+	c.send <- []byte{0}
+	c.send <- []byte{1}
+
+	h.deliver(c, []byte{2})
+
+	if len(c.send) != 2 {
+		t.Fatalf("send channel len = %d, want 2", len(c.send))
+	}
+	if got := <-c.send; got[0] != 1 {
+		t.Fatalf("oldest message = %v, want the second enqueued message to survive", got)
+	}
+	if got := <-c.send; got[0] != 2 {
+		t.Fatalf("newest message = %v, want the just-delivered message", got)
+	}
+}
+
+func TestDeliverPolicyDisconnect(t *testing.T) {
+	h := newTestHub(t, 1)
+	c := registerTestClient(t, h, PolicyDisconnect)
+	time.Sleep(10 * time.Millisecond) // let Run process the register
+	fillSendBuffer(c, 1)
+
+	h.deliver(c, []byte("overflow"))
+
+	if got := atomic.LoadUint64(&h.metrics.droppedTotal); got != 1 {
+		t.Fatalf("droppedTotal = %d, want 1", got)
+	}
+
+	h.mu.RLock()
+	_, stillRegistered := h.clients[c]
+	h.mu.RUnlock()
+	if stillRegistered {
+		t.Fatal("client should have been removed from the hub on PolicyDisconnect")
+	}
+	if !c.closed {
+		t.Fatal("client should have been closed on PolicyDisconnect")
+	}
+}
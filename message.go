@@ -0,0 +1,32 @@
+package hub
+
+import "time"
+
+// ActionMessage identifies the action a client wants the hub to take.
+type ActionMessage struct {
+	Action string `json:"action"`
+}
+
+// SubscriptionsMessage subscribes a client to one or more topics.
+// LastSeq optionally maps a topic to the last sequence number the client
+// already has, so the hub can replay anything published since.
+type SubscriptionsMessage struct {
+	ActionMessage
+	Topics  []string          `json:"topics"`
+	LastSeq map[string]uint64 `json:"lastSeq,omitempty"`
+}
+
+// PublishMessage carries a payload published to a topic. Seq and Created
+// are assigned by the hub once the message is persisted, so clients
+// publishing a message leave them zero.
+type PublishMessage struct {
+	ActionMessage
+	Topic   string    `json:"topic"`
+	Payload []byte    `json:"payload"`
+	Seq     uint64    `json:"seq,omitempty"`
+	Created time.Time `json:"created,omitempty"`
+	// Encoding names the compression applied to Payload, e.g.
+	// EncodingGzip, so subscribers know to decompress it. Empty means
+	// Payload is stored as-is.
+	Encoding string `json:"encoding,omitempty"`
+}
@@ -0,0 +1,76 @@
+package hub
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Metrics tracks backpressure and delivery counters for a Hub. It
+// implements http.Handler so operators can mount it wherever they want
+// to expose it, e.g. at /metrics, in Prometheus text exposition format.
+type Metrics struct {
+	droppedTotal uint64 // atomic
+
+	mu           sync.Mutex
+	queueDepth   map[string]int
+	latencySum   float64
+	latencyCount uint64
+}
+
+func newMetrics() *Metrics {
+	return &Metrics{queueDepth: make(map[string]int)}
+}
+
+func (m *Metrics) incDropped() {
+	atomic.AddUint64(&m.droppedTotal, 1)
+}
+
+func (m *Metrics) setQueueDepth(clientID string, depth int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.queueDepth[clientID] = depth
+}
+
+func (m *Metrics) clearQueueDepth(clientID string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.queueDepth, clientID)
+}
+
+func (m *Metrics) observeLatency(d time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.latencySum += d.Seconds()
+	m.latencyCount++
+}
+
+// ServeHTTP renders the hub's metrics in Prometheus text exposition
+// format.
+func (m *Metrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+
+	fmt.Fprintln(w, "# TYPE pubsub_client_dropped_total counter")
+	fmt.Fprintf(w, "pubsub_client_dropped_total %d\n", atomic.LoadUint64(&m.droppedTotal))
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	ids := make([]string, 0, len(m.queueDepth))
+	for id := range m.queueDepth {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	fmt.Fprintln(w, "# TYPE pubsub_client_send_queue_depth gauge")
+	for _, id := range ids {
+		fmt.Fprintf(w, "pubsub_client_send_queue_depth{client_id=%q} %d\n", id, m.queueDepth[id])
+	}
+
+	fmt.Fprintln(w, "# TYPE pubsub_publish_latency_seconds summary")
+	fmt.Fprintf(w, "pubsub_publish_latency_seconds_sum %f\n", m.latencySum)
+	fmt.Fprintf(w, "pubsub_publish_latency_seconds_count %d\n", m.latencyCount)
+}
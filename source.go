@@ -0,0 +1,151 @@
+package hub
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// initialSourceBackoff and maxSourceBackoff bound the exponential
+// backoff applied between restarts of a failed Source.
+const (
+	initialSourceBackoff = 500 * time.Millisecond
+	maxSourceBackoff     = 30 * time.Second
+)
+
+// Source is an external producer that injects messages into topics
+// without holding a *Client — a blockchain listener, a Kafka consumer, a
+// cron poller, etc.
+type Source interface {
+	// Run drives the source until ctx is canceled or it fails. emit
+	// publishes payload to topic through the same persistence and
+	// fan-out path as Hub.Publish.
+	Run(ctx context.Context, emit func(topic string, payload []byte)) error
+}
+
+// SourceStatus reports the current supervision state of a registered
+// Source.
+type SourceStatus struct {
+	Name        string    `json:"name"`
+	Running     bool      `json:"running"`
+	Restarts    int       `json:"restarts"`
+	LastError   string    `json:"lastError,omitempty"`
+	LastStarted time.Time `json:"lastStarted,omitempty"`
+}
+
+// sourceSupervisor restarts a Source with exponential backoff whenever
+// its Run method returns, and tracks its status for observability.
+type sourceSupervisor struct {
+	src    Source
+	cancel context.CancelFunc
+	emit   func(PublishMessage)
+
+	mu     sync.Mutex
+	status SourceStatus
+}
+
+// RegisterSource registers src under name and starts supervising it:
+// src.Run is restarted with exponential backoff whenever it returns,
+// until the Hub's Stop cancels every source. Registering a second Source
+// under a name already in use cancels and replaces the existing one
+// rather than leaking its supervisor goroutine.
+func (h *Hub) RegisterSource(name string, src Source) {
+	ctx, cancel := context.WithCancel(context.Background())
+	sup := &sourceSupervisor{
+		src:    src,
+		cancel: cancel,
+		emit:   h.Publish,
+		status: SourceStatus{Name: name},
+	}
+
+	h.mu.Lock()
+	prev := h.sources[name]
+	h.sources[name] = sup
+	h.mu.Unlock()
+
+	if prev != nil {
+		prev.cancel()
+	}
+
+	go sup.run(ctx)
+}
+
+// Stop cancels every registered Source.
+func (h *Hub) Stop() {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	for _, sup := range h.sources {
+		sup.cancel()
+	}
+}
+
+// SourceStatuses returns the current status of every registered Source,
+// sorted by name.
+func (h *Hub) SourceStatuses() []SourceStatus {
+	h.mu.RLock()
+	statuses := make([]SourceStatus, 0, len(h.sources))
+	for _, sup := range h.sources {
+		statuses = append(statuses, sup.snapshot())
+	}
+	h.mu.RUnlock()
+
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Name < statuses[j].Name })
+	return statuses
+}
+
+func (sup *sourceSupervisor) run(ctx context.Context) {
+	backoff := initialSourceBackoff
+	for {
+		if ctx.Err() != nil {
+			return
+		}
+
+		sup.setRunning(true)
+		err := sup.src.Run(ctx, func(topic string, payload []byte) {
+			sup.emit(PublishMessage{Topic: topic, Payload: payload})
+		})
+		sup.setRunning(false)
+
+		if ctx.Err() != nil {
+			return
+		}
+		sup.recordExit(err)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+		if backoff > maxSourceBackoff {
+			backoff = maxSourceBackoff
+		}
+	}
+}
+
+func (sup *sourceSupervisor) setRunning(running bool) {
+	sup.mu.Lock()
+	defer sup.mu.Unlock()
+	sup.status.Running = running
+	if running {
+		sup.status.LastStarted = time.Now()
+	}
+}
+
+func (sup *sourceSupervisor) recordExit(err error) {
+	sup.mu.Lock()
+	defer sup.mu.Unlock()
+	sup.status.Restarts++
+	if err != nil {
+		sup.status.LastError = err.Error()
+	} else {
+		sup.status.LastError = ""
+	}
+}
+
+func (sup *sourceSupervisor) snapshot() SourceStatus {
+	sup.mu.Lock()
+	defer sup.mu.Unlock()
+	return sup.status
+}
@@ -0,0 +1,102 @@
+package hub
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"testing"
+	"time"
+)
+
+// failingSource fails immediately every time it's run, reporting each
+// attempt on attempts so a test can observe the supervisor restarting it.
+type failingSource struct {
+	attempts chan struct{}
+}
+
+func (s *failingSource) Run(ctx context.Context, emit func(topic string, payload []byte)) error {
+	select {
+	case s.attempts <- struct{}{}:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	return errors.New("boom")
+}
+
+func TestSourceSupervisorRestartsWithBackoff(t *testing.T) {
+	h := NewHub(log.New(io.Discard, "", 0), Config{})
+	go h.Run()
+
+	src := &failingSource{attempts: make(chan struct{}, 8)}
+	h.RegisterSource("flaky", src)
+	defer h.Stop()
+
+	for i := 0; i < 2; i++ {
+		select {
+		case <-src.attempts:
+		case <-time.After(2 * time.Second):
+			t.Fatalf("source was not restarted after failure %d", i)
+		}
+	}
+
+	statuses := h.SourceStatuses()
+	if len(statuses) != 1 || statuses[0].Name != "flaky" {
+		t.Fatalf("SourceStatuses = %+v, want one status named flaky", statuses)
+	}
+	if statuses[0].Restarts < 1 {
+		t.Fatalf("Restarts = %d, want at least 1", statuses[0].Restarts)
+	}
+	if statuses[0].LastError == "" {
+		t.Fatalf("LastError should be recorded after a failed run")
+	}
+}
+
+// blockingSource runs until its context is canceled, then reports the
+// cancellation on canceled so a test can observe it.
+type blockingSource struct {
+	started  chan struct{}
+	canceled chan struct{}
+}
+
+func (s *blockingSource) Run(ctx context.Context, emit func(topic string, payload []byte)) error {
+	close(s.started)
+	<-ctx.Done()
+	close(s.canceled)
+	return ctx.Err()
+}
+
+func TestRegisterSourceReplacingNameCancelsPrevious(t *testing.T) {
+	h := NewHub(log.New(io.Discard, "", 0), Config{})
+	go h.Run()
+
+	first := &blockingSource{started: make(chan struct{}), canceled: make(chan struct{})}
+	h.RegisterSource("feed", first)
+
+	select {
+	case <-first.started:
+	case <-time.After(time.Second):
+		t.Fatal("first source never started")
+	}
+
+	second := &blockingSource{started: make(chan struct{}), canceled: make(chan struct{})}
+	h.RegisterSource("feed", second)
+	defer h.Stop()
+
+	select {
+	case <-first.canceled:
+	case <-time.After(time.Second):
+		t.Fatal("registering a duplicate name should cancel the previous supervisor")
+	}
+
+	select {
+	case <-second.started:
+	case <-time.After(time.Second):
+		t.Fatal("second source never started")
+	}
+
+	statuses := h.SourceStatuses()
+	if len(statuses) != 1 {
+		t.Fatalf("SourceStatuses = %+v, want exactly one entry for the replaced name", statuses)
+	}
+}
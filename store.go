@@ -0,0 +1,280 @@
+package hub
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Record is a single persisted message within a topic's log.
+type Record struct {
+	Seq     uint64
+	Created time.Time
+	Payload []byte
+}
+
+// Store persists published messages as an append-only, monotonically
+// increasing sequence per topic so subscribers can replay history from
+// any point.
+type Store interface {
+	// Append writes payload to topic's log and returns the sequence
+	// number assigned to it.
+	Append(topic string, payload []byte) (seq uint64, err error)
+	// Read returns every record in topic with Seq > fromSeq, in order.
+	Read(topic string, fromSeq uint64) ([]Record, error)
+	// Truncate discards records in topic with Seq < beforeSeq.
+	Truncate(topic string, beforeSeq uint64) error
+}
+
+// MemoryStore is a Store backed by per-topic slices held in memory. It is
+// the default Store for a Hub and is lost on restart.
+type MemoryStore struct {
+	mu     sync.Mutex
+	topics map[string]*memoryLog
+}
+
+type memoryLog struct {
+	nextSeq uint64
+	records []Record
+}
+
+// NewMemoryStore creates an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{topics: make(map[string]*memoryLog)}
+}
+
+func (m *MemoryStore) Append(topic string, payload []byte) (uint64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	l, ok := m.topics[topic]
+	if !ok {
+		l = &memoryLog{}
+		m.topics[topic] = l
+	}
+	l.nextSeq++
+	rec := Record{
+		Seq:     l.nextSeq,
+		Created: time.Now(),
+		Payload: append([]byte(nil), payload...),
+	}
+	l.records = append(l.records, rec)
+	return rec.Seq, nil
+}
+
+func (m *MemoryStore) Read(topic string, fromSeq uint64) ([]Record, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	l, ok := m.topics[topic]
+	if !ok {
+		return nil, nil
+	}
+	out := make([]Record, 0, len(l.records))
+	for _, r := range l.records {
+		if r.Seq > fromSeq {
+			out = append(out, r)
+		}
+	}
+	return out, nil
+}
+
+func (m *MemoryStore) Truncate(topic string, beforeSeq uint64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	l, ok := m.topics[topic]
+	if !ok {
+		return nil
+	}
+	kept := l.records[:0]
+	for _, r := range l.records {
+		if r.Seq >= beforeSeq {
+			kept = append(kept, r)
+		}
+	}
+	l.records = kept
+	return nil
+}
+
+// FileStore is a Store that persists each topic to its own append-only
+// file on disk, recovering sequence numbers by replaying the file on
+// first access.
+type FileStore struct {
+	dir string
+
+	mu   sync.Mutex
+	logs map[string]*fileLog
+}
+
+type fileLog struct {
+	f       *os.File
+	nextSeq uint64
+}
+
+// NewFileStore creates a FileStore rooted at dir, creating it if
+// necessary.
+func NewFileStore(dir string) (*FileStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &FileStore{dir: dir, logs: make(map[string]*fileLog)}, nil
+}
+
+// logPath returns the on-disk path for topic's log, replacing path
+// separators so hierarchical topic names don't create subdirectories.
+func (fs *FileStore) logPath(topic string) string {
+	safe := strings.ReplaceAll(topic, string(os.PathSeparator), "_")
+	return filepath.Join(fs.dir, safe+".log")
+}
+
+func (fs *FileStore) openLog(topic string) (*fileLog, error) {
+	if l, ok := fs.logs[topic]; ok {
+		return l, nil
+	}
+	f, err := os.OpenFile(fs.logPath(topic), os.O_CREATE|os.O_RDWR, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	records, err := readRecords(f)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	l := &fileLog{f: f}
+	if len(records) > 0 {
+		l.nextSeq = records[len(records)-1].Seq
+	}
+	fs.logs[topic] = l
+	return l, nil
+}
+
+func (fs *FileStore) Append(topic string, payload []byte) (uint64, error) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	l, err := fs.openLog(topic)
+	if err != nil {
+		return 0, err
+	}
+	rec := Record{Seq: l.nextSeq + 1, Created: time.Now(), Payload: payload}
+	if err := writeRecord(l.f, rec); err != nil {
+		return 0, err
+	}
+	l.nextSeq = rec.Seq
+	return rec.Seq, nil
+}
+
+func (fs *FileStore) Read(topic string, fromSeq uint64) ([]Record, error) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	l, err := fs.openLog(topic)
+	if err != nil {
+		return nil, err
+	}
+	records, err := readRecords(l.f)
+	if err != nil {
+		return nil, err
+	}
+	out := records[:0]
+	for _, r := range records {
+		if r.Seq > fromSeq {
+			out = append(out, r)
+		}
+	}
+	return out, nil
+}
+
+func (fs *FileStore) Truncate(topic string, beforeSeq uint64) error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	l, err := fs.openLog(topic)
+	if err != nil {
+		return err
+	}
+	records, err := readRecords(l.f)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.OpenFile(fs.logPath(topic)+".compact", os.O_CREATE|os.O_RDWR|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+	for _, r := range records {
+		if r.Seq >= beforeSeq {
+			if err := writeRecord(tmp, r); err != nil {
+				tmp.Close()
+				return err
+			}
+		}
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	l.f.Close()
+	if err := os.Rename(tmp.Name(), fs.logPath(topic)); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(fs.logPath(topic), os.O_CREATE|os.O_RDWR, 0o644)
+	if err != nil {
+		return err
+	}
+	l.f = f
+	return nil
+}
+
+// writeRecord appends rec to f as [seq][created unix nano][payload
+// len][payload], all little-endian.
+func writeRecord(f *os.File, rec Record) error {
+	if _, err := f.Seek(0, io.SeekEnd); err != nil {
+		return err
+	}
+	header := make([]byte, 20)
+	binary.LittleEndian.PutUint64(header[0:8], rec.Seq)
+	binary.LittleEndian.PutUint64(header[8:16], uint64(rec.Created.UnixNano()))
+	binary.LittleEndian.PutUint32(header[16:20], uint32(len(rec.Payload)))
+	if _, err := f.Write(header); err != nil {
+		return err
+	}
+	_, err := f.Write(rec.Payload)
+	return err
+}
+
+// readRecords reads every record from the start of f.
+func readRecords(f *os.File) ([]Record, error) {
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	r := bufio.NewReader(f)
+	var records []Record
+	header := make([]byte, 20)
+	for {
+		if _, err := io.ReadFull(r, header); err != nil {
+			if err == io.EOF {
+				break
+			}
+			return nil, err
+		}
+		seq := binary.LittleEndian.Uint64(header[0:8])
+		created := int64(binary.LittleEndian.Uint64(header[8:16]))
+		size := binary.LittleEndian.Uint32(header[16:20])
+		payload := make([]byte, size)
+		if _, err := io.ReadFull(r, payload); err != nil {
+			return nil, err
+		}
+		records = append(records, Record{
+			Seq:     seq,
+			Created: time.Unix(0, created),
+			Payload: payload,
+		})
+	}
+	return records, nil
+}
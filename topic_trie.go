@@ -0,0 +1,94 @@
+package hub
+
+import "strings"
+
+// wildcardSingle matches exactly one topic segment, mirroring MQTT's `+`.
+const wildcardSingle = "+"
+
+// wildcardMulti matches the remainder of a topic, including zero
+// additional segments, mirroring MQTT's `#`. It is only meaningful as
+// the final segment of a pattern.
+const wildcardMulti = "#"
+
+// topicTrie indexes client subscriptions by topic pattern so Publish can
+// find matching subscribers in time proportional to the topic's depth
+// rather than the number of subscriptions.
+type topicTrie struct {
+	root trieNode
+}
+
+type trieNode struct {
+	children map[string]*trieNode
+	clients  map[*Client]bool
+}
+
+func newTopicTrie() *topicTrie {
+	return &topicTrie{}
+}
+
+// insert registers c against pattern.
+func (t *topicTrie) insert(pattern string, c *Client) {
+	node := &t.root
+	for _, seg := range strings.Split(pattern, "/") {
+		if node.children == nil {
+			node.children = make(map[string]*trieNode)
+		}
+		child, ok := node.children[seg]
+		if !ok {
+			child = &trieNode{}
+			node.children[seg] = child
+		}
+		node = child
+	}
+	if node.clients == nil {
+		node.clients = make(map[*Client]bool)
+	}
+	node.clients[c] = true
+}
+
+// remove deregisters c from pattern, pruning any now-empty nodes.
+func (t *topicTrie) remove(pattern string, c *Client) {
+	removeAt(&t.root, strings.Split(pattern, "/"), c)
+}
+
+func removeAt(node *trieNode, segs []string, c *Client) bool {
+	if len(segs) == 0 {
+		delete(node.clients, c)
+	} else if child, ok := node.children[segs[0]]; ok {
+		if removeAt(child, segs[1:], c) {
+			delete(node.children, segs[0])
+		}
+	}
+	return len(node.clients) == 0 && len(node.children) == 0
+}
+
+// match returns the set of clients subscribed to a pattern matching
+// topic, which must not itself contain wildcards. A client subscribed
+// via more than one matching pattern is only included once.
+func (t *topicTrie) match(topic string) map[*Client]bool {
+	result := make(map[*Client]bool)
+	matchAt(&t.root, strings.Split(topic, "/"), result)
+	return result
+}
+
+func matchAt(node *trieNode, segs []string, result map[*Client]bool) {
+	if node == nil {
+		return
+	}
+	// "#" matches this node and everything beneath it, regardless of
+	// how many segments remain.
+	if multi, ok := node.children[wildcardMulti]; ok {
+		for c := range multi.clients {
+			result[c] = true
+		}
+	}
+	if len(segs) == 0 {
+		for c := range node.clients {
+			result[c] = true
+		}
+		return
+	}
+	seg, rest := segs[0], segs[1:]
+	matchAt(node.children[seg], rest, result)
+	matchAt(node.children[wildcardSingle], rest, result)
+}
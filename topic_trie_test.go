@@ -0,0 +1,57 @@
+package hub
+
+import "testing"
+
+func TestTopicTrieMatch(t *testing.T) {
+	trie := newTopicTrie()
+	c := &Client{ID: "c1"}
+
+	trie.insert("orders/+/created", c)
+	trie.insert("logs/#", c)
+
+	cases := []struct {
+		topic string
+		want  bool
+	}{
+		{"orders/42/created", true},
+		{"orders/42/updated", false},
+		{"logs", true},
+		{"logs/app/error", true},
+		{"other", false},
+	}
+	for _, tc := range cases {
+		matched := trie.match(tc.topic)
+		if matched[c] != tc.want {
+			t.Errorf("match(%q)[c] = %v, want %v", tc.topic, matched[c], tc.want)
+		}
+	}
+}
+
+func TestTopicTrieOverlappingSubscriptionsDeliverOnce(t *testing.T) {
+	trie := newTopicTrie()
+	c := &Client{ID: "c1"}
+
+	trie.insert("a/+", c)
+	trie.insert("a/#", c)
+
+	matched := trie.match("a/b")
+	if len(matched) != 1 {
+		t.Fatalf("expected exactly one recipient, got %d", len(matched))
+	}
+	if !matched[c] {
+		t.Fatalf("expected client to match a/b via overlapping patterns")
+	}
+}
+
+func TestTopicTrieRemove(t *testing.T) {
+	trie := newTopicTrie()
+	c := &Client{ID: "c1"}
+
+	trie.insert("a/+", c)
+	trie.remove("a/+", c)
+
+	matched := trie.match("a/b")
+	if matched[c] {
+		t.Fatalf("expected client to be removed from a/+")
+	}
+}